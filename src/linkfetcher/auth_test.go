@@ -0,0 +1,71 @@
+package main
+
+import "testing"
+
+func TestParseChallengeBasic(t *testing.T) {
+
+	c, err := parseChallenge(`Basic realm="registry"`)
+	if err != nil {
+		t.Fatalf("parseChallenge: %v", err)
+	}
+
+	if c.Scheme != "Basic" || c.Realm != "registry" {
+		t.Fatalf("got %+v, want Scheme=Basic Realm=registry", c)
+	}
+}
+
+func TestParseChallengeBearer(t *testing.T) {
+
+	c, err := parseChallenge(`Bearer realm="https://auth.example.com/token",service="registry.example.com",scope="repo:foo:pull"`)
+	if err != nil {
+		t.Fatalf("parseChallenge: %v", err)
+	}
+
+	if c.Scheme != "Bearer" {
+		t.Fatalf("Scheme = %q, want Bearer", c.Scheme)
+	}
+	if c.Realm != "https://auth.example.com/token" {
+		t.Fatalf("Realm = %q", c.Realm)
+	}
+	if c.Service != "registry.example.com" {
+		t.Fatalf("Service = %q", c.Service)
+	}
+	if c.Scope != "repo:foo:pull" {
+		t.Fatalf("Scope = %q", c.Scope)
+	}
+}
+
+func TestParseChallengeSchemeOnly(t *testing.T) {
+
+	c, err := parseChallenge("Negotiate")
+	if err != nil {
+		t.Fatalf("parseChallenge: %v", err)
+	}
+	if c.Scheme != "Negotiate" || c.Realm != "" {
+		t.Fatalf("got %+v, want bare Scheme=Negotiate", c)
+	}
+}
+
+func TestParseChallengeEmpty(t *testing.T) {
+	if _, err := parseChallenge(""); err == nil {
+		t.Fatalf("parseChallenge(\"\") should error")
+	}
+}
+
+func TestNewCredentialProviderCommandRequiresRegisteredName(t *testing.T) {
+
+	if _, err := newCredentialProvider(AuthConfig{Type: "command", Name: "does-not-exist"}); err == nil {
+		t.Fatalf("expected an error for an unregistered command provider name")
+	}
+
+	registerCommandProvider("test-helper", []string{"echo", "token"})
+	defer delete(commandProviders, "test-helper")
+
+	provider, err := newCredentialProvider(AuthConfig{Type: "command", Name: "test-helper"})
+	if err != nil {
+		t.Fatalf("newCredentialProvider: %v", err)
+	}
+	if _, ok := provider.(*commandProvider); !ok {
+		t.Fatalf("got %T, want *commandProvider", provider)
+	}
+}
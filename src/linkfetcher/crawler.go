@@ -0,0 +1,442 @@
+package main
+
+import (
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// CrawlRequest describes a recursive crawl starting from a set of seeds.
+type CrawlRequest struct {
+	Seeds       []string `json:"seeds"`
+	MaxDepth    int      `json:"max_depth"`
+	MaxPages    int      `json:"max_pages"`
+	SameHost    bool     `json:"same_host"`
+	PoliteDelay Duration `json:"polite_delay,omitempty"`
+}
+
+// CrawlNode is one fetched page, linked back to the page that
+// discovered it. The response is a flat list of these; Depth and
+// Parent let a caller reconstruct the tree.
+type CrawlNode struct {
+	URL    string `json:"url"`
+	Depth  int    `json:"depth"`
+	Parent string `json:"parent,omitempty"`
+	Meta   Meta   `json:"meta"`
+}
+
+// CrawlResponse is the result of a recursive crawl.
+type CrawlResponse []*CrawlNode
+
+// crawlHandle is the GIN handler for POST /crawl.
+func (fs *fetcherServer) crawlHandle(c *gin.Context) {
+
+	var request CrawlRequest
+
+	err := c.BindJSON(&request)
+	if err != nil {
+		log.Println("Error decoding crawl request: %v", err)
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	result, err := fs.crawl(c.Request.Context(), request)
+	if err != nil {
+		log.Println("Unrecoverable error while crawling: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// crawl recursively follows links discovered in fetched HTML,
+// starting from request.Seeds, until MaxDepth/MaxPages is reached,
+// ctx is cancelled, or the frontier runs dry. Up to Workers pages are
+// fetched concurrently, each through the existing worker pool, so the
+// crawl actually benefits from the pool's concurrency instead of
+// fetching one page at a time.
+func (fs *fetcherServer) crawl(ctx context.Context, request CrawlRequest) (*CrawlResponse, error) {
+
+	maxPages := request.MaxPages
+	if maxPages <= 0 {
+		maxPages = 100
+	}
+
+	concurrency := Workers
+	if concurrency <= 0 {
+		concurrency = 1
+	}
+
+	var (
+		fr      = newFrontier(maxPages * 4)
+		limiter = newHostLimiter(time.Duration(request.PoliteDelay))
+		robots  = newRobotsCache()
+
+		mu     sync.Mutex
+		result = make(CrawlResponse, 0, maxPages)
+
+		wg       sync.WaitGroup
+		inflight int64
+		sem      = make(chan struct{}, concurrency)
+	)
+
+	for _, seed := range request.Seeds {
+		u, err := url.Parse(seed)
+		if err != nil {
+			continue
+		}
+		fr.push(frontierItem{url: seed, seedHost: u.Host})
+	}
+
+dispatch:
+	for {
+		select {
+		case <-ctx.Done():
+			break dispatch
+		default:
+		}
+
+		mu.Lock()
+		full := len(result) >= maxPages
+		mu.Unlock()
+		if full {
+			break
+		}
+
+		it, ok := fr.pop()
+		if !ok {
+			// The frontier is empty for now, but an in-flight fetch
+			// may still push more items into it; only stop once
+			// nothing is left running.
+			if atomic.LoadInt64(&inflight) == 0 {
+				break
+			}
+			time.Sleep(10 * time.Millisecond)
+			continue
+		}
+
+		atomic.AddInt64(&inflight, 1)
+		sem <- struct{}{}
+		wg.Add(1)
+
+		go func(it frontierItem) {
+			defer wg.Done()
+			defer atomic.AddInt64(&inflight, -1)
+			defer func() { <-sem }()
+
+			u, err := url.Parse(it.url)
+			if err != nil {
+				return
+			}
+
+			if request.SameHost && !strings.EqualFold(u.Host, it.seedHost) {
+				return
+			}
+
+			if !robots.allowed(ctx, u) {
+				return
+			}
+
+			limiter.wait(ctx, u.Host)
+
+			mu.Lock()
+			full := len(result) >= maxPages
+			mu.Unlock()
+			if full {
+				return
+			}
+
+			resp := newQueueRequestCtx(ctx, it.url, nil, nil, 0).do(fs)
+
+			mu.Lock()
+			if len(result) < maxPages {
+				result = append(result, &CrawlNode{
+					URL:    it.url,
+					Depth:  it.depth,
+					Parent: it.parent,
+					Meta:   resp.Meta,
+				})
+			}
+			mu.Unlock()
+
+			if it.depth >= request.MaxDepth {
+				return
+			}
+
+			for _, link := range resp.Links {
+				abs, err := u.Parse(link)
+				if err != nil {
+					continue
+				}
+				abs.Fragment = ""
+				fr.push(frontierItem{url: abs.String(), depth: it.depth + 1, parent: it.url, seedHost: it.seedHost})
+			}
+		}(it)
+	}
+
+	wg.Wait()
+
+	return &result, ctx.Err()
+}
+
+type frontierItem struct {
+	url    string
+	depth  int
+	parent string
+
+	// seedHost is the host of the seed this item's sub-crawl started
+	// from, inherited by every link discovered under it. SameHost
+	// compares against this instead of request.Seeds[0], so multiple
+	// seeds on different hosts each stay within their own host
+	// instead of everything being compared to the first seed.
+	seedHost string
+}
+
+// frontier is a bounded, deduplicating crawl queue. It is modeled on
+// the XQ ring-buffer pattern: a fixed-size slice used as a circular
+// buffer, paired with a seen-set keyed on Hash(url) so an already
+// queued (or already crawled) URL is never pushed twice.
+type frontier struct {
+	mu    sync.Mutex
+	buf   []frontierItem
+	head  int
+	tail  int
+	count int
+	seen  map[string]struct{}
+}
+
+func newFrontier(capacity int) *frontier {
+	if capacity <= 0 {
+		capacity = 1
+	}
+	return &frontier{
+		buf:  make([]frontierItem, capacity),
+		seen: make(map[string]struct{}),
+	}
+}
+
+// push enqueues it unless its hash has already been seen or the ring
+// is full. Returns false if the item was dropped.
+func (f *frontier) push(it frontierItem) bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	h := Hash(it.url)
+	if _, ok := f.seen[h]; ok {
+		return false
+	}
+	if f.count == len(f.buf) {
+		return false
+	}
+
+	f.seen[h] = struct{}{}
+	f.buf[f.tail] = it
+	f.tail = (f.tail + 1) % len(f.buf)
+	f.count++
+	return true
+}
+
+// pop dequeues the oldest item. ok is false if the frontier is empty.
+func (f *frontier) pop() (it frontierItem, ok bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.count == 0 {
+		return frontierItem{}, false
+	}
+
+	it = f.buf[f.head]
+	f.head = (f.head + 1) % len(f.buf)
+	f.count--
+	return it, true
+}
+
+// Hash normalizes a URL and returns a stable digest used to dedupe
+// the crawl frontier: host is lowercased, the scheme's default port
+// is dropped, the fragment is stripped, and query parameters are
+// sorted so equivalent URLs collide.
+func Hash(raw string) string {
+
+	u, err := url.Parse(raw)
+	if err != nil {
+		return raw
+	}
+
+	u.Host = strings.ToLower(u.Host)
+	if host, port, err := net.SplitHostPort(u.Host); err == nil {
+		if (u.Scheme == "http" && port == "80") || (u.Scheme == "https" && port == "443") {
+			u.Host = host
+		}
+	}
+	u.Fragment = ""
+
+	if u.RawQuery != "" {
+		q := u.Query()
+
+		keys := make([]string, 0, len(q))
+		for k := range q {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+
+		sorted := url.Values{}
+		for _, k := range keys {
+			sort.Strings(q[k])
+			for _, v := range q[k] {
+				sorted.Add(k, v)
+			}
+		}
+		u.RawQuery = sorted.Encode()
+	}
+
+	sum := sha1.Sum([]byte(u.String()))
+	return hex.EncodeToString(sum[:])
+}
+
+// hostLimiter enforces a minimum delay between requests to the same
+// host, so a crawl stays polite even though fetches share a worker
+// pool across many hosts at once.
+type hostLimiter struct {
+	mu    sync.Mutex
+	delay time.Duration
+	last  map[string]time.Time
+}
+
+func newHostLimiter(delay time.Duration) *hostLimiter {
+	if delay <= 0 {
+		delay = time.Second
+	}
+	return &hostLimiter{
+		delay: delay,
+		last:  make(map[string]time.Time),
+	}
+}
+
+func (hl *hostLimiter) wait(ctx context.Context, host string) {
+	// Reserve this caller's slot under the lock, so concurrent callers
+	// for the same host are spaced delay apart instead of all seeing
+	// the same last time and proceeding together.
+	hl.mu.Lock()
+	next := time.Now()
+	if last, ok := hl.last[host]; ok && last.Add(hl.delay).After(next) {
+		next = last.Add(hl.delay)
+	}
+	hl.last[host] = next
+	hl.mu.Unlock()
+
+	if remaining := time.Until(next); remaining > 0 {
+		timer := time.NewTimer(remaining)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+		}
+	}
+}
+
+// robotsCache fetches and caches a per-host robots.txt policy so the
+// crawler only asks each host once.
+type robotsCache struct {
+	mu    sync.Mutex
+	rules map[string]*robotsPolicy
+}
+
+func newRobotsCache() *robotsCache {
+	return &robotsCache{rules: make(map[string]*robotsPolicy)}
+}
+
+type robotsPolicy struct {
+	disallow []string
+}
+
+func (rc *robotsCache) allowed(ctx context.Context, u *url.URL) bool {
+
+	// The lock is held across the fetch so concurrent goroutines that
+	// miss the cache for the same host don't each fetch robots.txt.
+	rc.mu.Lock()
+	policy, ok := rc.rules[u.Host]
+	if !ok {
+		policy = rc.fetch(ctx, u)
+		rc.rules[u.Host] = policy
+	}
+	rc.mu.Unlock()
+
+	for _, prefix := range policy.disallow {
+		if prefix != "" && strings.HasPrefix(u.Path, prefix) {
+			return false
+		}
+	}
+	return true
+}
+
+func (rc *robotsCache) fetch(ctx context.Context, u *url.URL) *robotsPolicy {
+
+	robotsURL := fmt.Sprintf("%s://%s/robots.txt", u.Scheme, u.Host)
+
+	req, err := http.NewRequest("GET", robotsURL, nil)
+	if err != nil {
+		return &robotsPolicy{}
+	}
+
+	resp, err := http.DefaultClient.Do(req.WithContext(ctx))
+	if err != nil {
+		return &robotsPolicy{}
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return &robotsPolicy{}
+	}
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return &robotsPolicy{}
+	}
+
+	return &robotsPolicy{disallow: parseRobots(body)}
+}
+
+// parseRobots extracts Disallow prefixes from the User-agent: * group.
+// It is deliberately minimal: no wildcard matching or Allow
+// precedence, just enough to keep a crawl off paths an operator
+// clearly blocked.
+func parseRobots(body []byte) []string {
+
+	var (
+		disallow []string
+		inStar   bool
+	)
+
+	for _, line := range strings.Split(string(body), "\n") {
+		line = strings.TrimSpace(line)
+		lower := strings.ToLower(line)
+
+		switch {
+		case strings.HasPrefix(lower, "user-agent:"):
+			agent := strings.TrimSpace(line[len("user-agent:"):])
+			inStar = agent == "*"
+		case inStar && strings.HasPrefix(lower, "disallow:"):
+			disallow = append(disallow, strings.TrimSpace(line[len("disallow:"):]))
+		}
+	}
+
+	return disallow
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestHashNormalizesEquivalentURLs(t *testing.T) {
+
+	cases := []struct {
+		a, b string
+	}{
+		{"http://Example.com:80/path", "http://example.com/path"},
+		{"https://example.com:443/path", "https://example.com/path"},
+		{"https://example.com/path#section", "https://example.com/path"},
+		{"https://example.com/path?b=2&a=1", "https://example.com/path?a=1&b=2"},
+	}
+
+	for _, c := range cases {
+		if Hash(c.a) != Hash(c.b) {
+			t.Errorf("Hash(%q) != Hash(%q), want equal", c.a, c.b)
+		}
+	}
+
+	if Hash("https://example.com/a") == Hash("https://example.com/b") {
+		t.Errorf("Hash collided for distinct paths")
+	}
+}
+
+func TestFrontierDedupesByHash(t *testing.T) {
+
+	fr := newFrontier(10)
+
+	if !fr.push(frontierItem{url: "https://example.com/page"}) {
+		t.Fatalf("first push of a new URL was dropped")
+	}
+	if fr.push(frontierItem{url: "https://Example.com:443/page"}) {
+		t.Fatalf("push of an equivalent (same-hash) URL should be deduped")
+	}
+
+	it, ok := fr.pop()
+	if !ok || it.url != "https://example.com/page" {
+		t.Fatalf("pop returned %+v, %v; want the single pushed item", it, ok)
+	}
+
+	if _, ok := fr.pop(); ok {
+		t.Fatalf("pop succeeded on an empty frontier")
+	}
+}
+
+func TestFrontierDropsWhenFull(t *testing.T) {
+
+	fr := newFrontier(1)
+
+	if !fr.push(frontierItem{url: "https://example.com/a"}) {
+		t.Fatalf("push into an empty ring was dropped")
+	}
+	if fr.push(frontierItem{url: "https://example.com/b"}) {
+		t.Fatalf("push into a full ring should be dropped, not accepted")
+	}
+}
+
+// TestCrawlSameHostPerSeed guards against a regression where SameHost
+// compared every discovered URL against request.Seeds[0] only: with
+// multiple seeds on different hosts, every page belonging to any seed
+// but the first was dropped. The fixture server answers under two
+// distinct hostnames (127.0.0.1 and localhost) that both resolve to
+// the same listener, so each seed's sub-crawl should stay on its own
+// host and only cross-link URLs should be dropped.
+func TestCrawlSameHostPerSeed(t *testing.T) {
+
+	var addr string
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/a", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, `<a href="http://127.0.0.1:%s/a2">same-host</a> <a href="http://localhost:%s/x">cross-host</a>`, addr, addr)
+	})
+	mux.HandleFunc("/a2", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `ok`)
+	})
+	mux.HandleFunc("/b", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, `<a href="http://localhost:%s/b2">same-host</a>`, addr)
+	})
+	mux.HandleFunc("/b2", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `ok`)
+	})
+
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	addr = strings.TrimPrefix(srv.URL, "http://127.0.0.1:")
+
+	store := newTestStore(t)
+	fs, err := newFetcher(store)
+	if err != nil {
+		t.Fatalf("newFetcher: %v", err)
+	}
+
+	req := CrawlRequest{
+		Seeds:    []string{"http://127.0.0.1:" + addr + "/a", "http://localhost:" + addr + "/b"},
+		MaxDepth: 1,
+		MaxPages: 10,
+		SameHost: true,
+	}
+
+	resp, err := fs.crawl(context.Background(), req)
+	if err != nil {
+		t.Fatalf("crawl: %v", err)
+	}
+
+	seen := map[string]bool{}
+	for _, node := range *resp {
+		seen[node.URL] = true
+	}
+
+	for _, want := range []string{"http://127.0.0.1:" + addr + "/a", "http://127.0.0.1:" + addr + "/a2", "http://localhost:" + addr + "/b", "http://localhost:" + addr + "/b2"} {
+		if !seen[want] {
+			t.Errorf("crawl result missing %s: %v", want, seen)
+		}
+	}
+
+	if seen["http://localhost:"+addr+"/x"] {
+		t.Errorf("crawl followed a cross-seed-host link under SameHost")
+	}
+}
@@ -0,0 +1,397 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"log"
+	"regexp"
+	"sort"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// Extractor turns a fetched body into a structured, named result.
+// Match reports whether it applies to a given Content-Type; a
+// Request opts a URL into an extractor by name.
+type Extractor interface {
+	Name() string
+	Match(contentType string) bool
+	Extract(body []byte, meta *Meta) (interface{}, error)
+}
+
+// extractorRegistry looks extractors up by name.
+type extractorRegistry struct {
+	byName map[string]Extractor
+}
+
+func newExtractorRegistry(extractors ...Extractor) *extractorRegistry {
+	r := &extractorRegistry{byName: make(map[string]Extractor, len(extractors))}
+	for _, e := range extractors {
+		r.byName[e.Name()] = e
+	}
+	return r
+}
+
+// defaultExtractorRegistry is the set of built-in Extractors wired
+// into newFetcher.
+func defaultExtractorRegistry() *extractorRegistry {
+	return newExtractorRegistry(
+		htmlExtractor{},
+		jsonExtractor{},
+		feedExtractor{},
+		sitemapExtractor{},
+		textExtractor{},
+	)
+}
+
+// run applies the named extractors to body, skipping any that are
+// unknown or reject meta.ContentType, and returns a name -> result
+// map suitable for ResponseItem.Extracted. A failing extractor is
+// logged and skipped rather than failing the whole fetch.
+func (r *extractorRegistry) run(names []string, body []byte, meta *Meta) map[string]interface{} {
+
+	if len(names) == 0 {
+		return nil
+	}
+
+	out := make(map[string]interface{}, len(names))
+	for _, name := range names {
+		e, ok := r.byName[name]
+		if !ok || !e.Match(meta.ContentType) {
+			continue
+		}
+
+		result, err := e.Extract(body, meta)
+		if err != nil {
+			log.Printf("extractor %q failed for %s: %v", name, meta.ContentType, err)
+			continue
+		}
+
+		out[name] = result
+	}
+
+	return out
+}
+
+// HTMLExtracted is the result of the "html" extractor.
+type HTMLExtracted struct {
+	Tags  []Element         `json:"tags"`
+	Title string            `json:"title,omitempty"`
+	Meta  map[string]string `json:"meta,omitempty"`
+	Links []string          `json:"links,omitempty"`
+	Text  string            `json:"text,omitempty"`
+}
+
+type htmlExtractor struct{}
+
+func (htmlExtractor) Name() string { return "html" }
+
+func (htmlExtractor) Match(contentType string) bool {
+	return strings.HasPrefix(contentType, "text/html")
+}
+
+func (htmlExtractor) Extract(body []byte, meta *Meta) (interface{}, error) {
+
+	tags, links, err := countTags(body)
+	if err != nil {
+		return nil, err
+	}
+
+	title, metaPairs, text := parseHTMLDocument(body)
+
+	return &HTMLExtracted{
+		Tags:  tags,
+		Title: title,
+		Meta:  metaPairs,
+		Links: links,
+		Text:  text,
+	}, nil
+}
+
+// parseHTMLDocument walks body once and pulls out the <title>, the
+// meta name/property -> content pairs, and a readability-style main
+// text: the concatenated text nodes outside script/style/nav/
+// header/footer.
+func parseHTMLDocument(body []byte) (title string, metaPairs map[string]string, text string) {
+
+	var (
+		z         = html.NewTokenizer(bytes.NewReader(body))
+		tb        strings.Builder
+		skipDepth int
+		inTitle   bool
+	)
+
+	metaPairs = map[string]string{}
+	skipTags := map[string]bool{"script": true, "style": true, "nav": true, "header": true, "footer": true}
+
+	for {
+		switch z.Next() {
+		case html.ErrorToken:
+			return title, metaPairs, strings.TrimSpace(tb.String())
+
+		case html.StartTagToken, html.SelfClosingTagToken:
+			name, hasAttr := z.TagName()
+			tag := string(name)
+
+			if tag == "title" {
+				inTitle = true
+			}
+
+			if tag == "meta" {
+				var key, content string
+				for hasAttr {
+					var k, v []byte
+					k, v, hasAttr = z.TagAttr()
+					switch string(k) {
+					case "name", "property":
+						key = string(v)
+					case "content":
+						content = string(v)
+					}
+				}
+				if key != "" {
+					metaPairs[key] = content
+				}
+			}
+
+			if skipTags[tag] {
+				skipDepth++
+			}
+
+		case html.EndTagToken:
+			name, _ := z.TagName()
+			tag := string(name)
+
+			if tag == "title" {
+				inTitle = false
+			}
+			if skipTags[tag] && skipDepth > 0 {
+				skipDepth--
+			}
+
+		case html.TextToken:
+			chunk := strings.TrimSpace(string(z.Text()))
+			if chunk == "" {
+				continue
+			}
+			if inTitle {
+				title = chunk
+			}
+			if skipDepth == 0 {
+				tb.WriteString(chunk)
+				tb.WriteString(" ")
+			}
+		}
+	}
+}
+
+// JSONExtracted is the result of the "json" extractor: the top-level
+// key set (for an object document) and the document's nesting depth.
+type JSONExtracted struct {
+	Keys  []string `json:"keys,omitempty"`
+	Depth int      `json:"depth"`
+}
+
+type jsonExtractor struct{}
+
+func (jsonExtractor) Name() string { return "json" }
+
+func (jsonExtractor) Match(contentType string) bool {
+	return strings.HasPrefix(contentType, "application/json") || strings.HasPrefix(contentType, "text/json")
+}
+
+func (jsonExtractor) Extract(body []byte, meta *Meta) (interface{}, error) {
+
+	var doc interface{}
+	if err := json.Unmarshal(body, &doc); err != nil {
+		return nil, err
+	}
+
+	result := &JSONExtracted{Depth: jsonDepth(doc, 0)}
+
+	if obj, ok := doc.(map[string]interface{}); ok {
+		for k := range obj {
+			result.Keys = append(result.Keys, k)
+		}
+		sort.Strings(result.Keys)
+	}
+
+	return result, nil
+}
+
+func jsonDepth(v interface{}, depth int) int {
+	switch val := v.(type) {
+	case map[string]interface{}:
+		max := depth
+		for _, child := range val {
+			if d := jsonDepth(child, depth+1); d > max {
+				max = d
+			}
+		}
+		return max
+
+	case []interface{}:
+		max := depth
+		for _, child := range val {
+			if d := jsonDepth(child, depth+1); d > max {
+				max = d
+			}
+		}
+		return max
+
+	default:
+		return depth
+	}
+}
+
+// FeedItem is one entry of an RSS/Atom feed.
+type FeedItem struct {
+	Title string `json:"title,omitempty"`
+	Link  string `json:"link,omitempty"`
+}
+
+// FeedExtracted is the result of the "feed" extractor.
+type FeedExtracted struct {
+	Title string     `json:"title,omitempty"`
+	Items []FeedItem `json:"items,omitempty"`
+}
+
+type feedExtractor struct{}
+
+func (feedExtractor) Name() string { return "feed" }
+
+func (feedExtractor) Match(contentType string) bool {
+	return strings.Contains(contentType, "xml") ||
+		strings.Contains(contentType, "rss") ||
+		strings.Contains(contentType, "atom")
+}
+
+func (feedExtractor) Extract(body []byte, meta *Meta) (interface{}, error) {
+
+	var rss struct {
+		Channel struct {
+			Title string `xml:"title"`
+			Items []struct {
+				Title string `xml:"title"`
+				Link  string `xml:"link"`
+			} `xml:"item"`
+		} `xml:"channel"`
+	}
+
+	if err := xml.Unmarshal(body, &rss); err == nil && len(rss.Channel.Items) > 0 {
+		out := &FeedExtracted{Title: rss.Channel.Title}
+		for _, item := range rss.Channel.Items {
+			out.Items = append(out.Items, FeedItem{Title: item.Title, Link: item.Link})
+		}
+		return out, nil
+	}
+
+	var atom struct {
+		Title   string `xml:"title"`
+		Entries []struct {
+			Title string `xml:"title"`
+			Link  struct {
+				Href string `xml:"href,attr"`
+			} `xml:"link"`
+		} `xml:"entry"`
+	}
+
+	if err := xml.Unmarshal(body, &atom); err != nil {
+		return nil, err
+	}
+
+	out := &FeedExtracted{Title: atom.Title}
+	for _, entry := range atom.Entries {
+		out.Items = append(out.Items, FeedItem{Title: entry.Title, Link: entry.Link.Href})
+	}
+	return out, nil
+}
+
+// SitemapExtracted is the result of the "sitemap" extractor: the
+// <loc> URLs of a sitemap.xml, useful as seeds for the crawler.
+type SitemapExtracted struct {
+	URLs []string `json:"urls,omitempty"`
+}
+
+type sitemapExtractor struct{}
+
+func (sitemapExtractor) Name() string { return "sitemap" }
+
+func (sitemapExtractor) Match(contentType string) bool {
+	return strings.Contains(contentType, "xml")
+}
+
+func (sitemapExtractor) Extract(body []byte, meta *Meta) (interface{}, error) {
+
+	var doc struct {
+		URLs []struct {
+			Loc string `xml:"loc"`
+		} `xml:"url"`
+	}
+
+	if err := xml.Unmarshal(body, &doc); err != nil {
+		return nil, err
+	}
+
+	out := &SitemapExtracted{}
+	for _, u := range doc.URLs {
+		out.URLs = append(out.URLs, u.Loc)
+	}
+	return out, nil
+}
+
+var wordRe = regexp.MustCompile(`\S+`)
+
+// TextExtracted is the result of the "text" extractor: a word count
+// and a coarse, heuristically detected language.
+type TextExtracted struct {
+	Words    int    `json:"words"`
+	Language string `json:"language,omitempty"`
+}
+
+type textExtractor struct{}
+
+func (textExtractor) Name() string { return "text" }
+
+func (textExtractor) Match(contentType string) bool {
+	return strings.HasPrefix(contentType, "text/plain")
+}
+
+func (textExtractor) Extract(body []byte, meta *Meta) (interface{}, error) {
+	return &TextExtracted{
+		Words:    len(wordRe.FindAll(body, -1)),
+		Language: detectLanguage(body),
+	}, nil
+}
+
+// detectLanguage is a deliberately crude heuristic: it counts a
+// handful of stop words per language and returns whichever scores
+// highest. Good enough to tell obviously-English from obviously-not
+// without pulling in a real language-ID model.
+func detectLanguage(body []byte) string {
+
+	stopWords := map[string][]string{
+		"en": {" the ", " and ", " is ", " of ", " to "},
+		"es": {" el ", " la ", " de ", " y ", " que "},
+		"fr": {" le ", " la ", " et ", " de ", " que "},
+		"de": {" der ", " die ", " und ", " ist ", " das "},
+	}
+
+	text := " " + strings.ToLower(string(body)) + " "
+
+	var best string
+	var bestScore int
+	for lang, words := range stopWords {
+		var score int
+		for _, w := range words {
+			score += strings.Count(text, w)
+		}
+		if score > bestScore {
+			best, bestScore = lang, score
+		}
+	}
+
+	return best
+}
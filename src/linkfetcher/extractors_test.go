@@ -0,0 +1,139 @@
+package main
+
+import (
+	"sort"
+	"strings"
+	"testing"
+)
+
+func TestHTMLExtractorTitleMetaAndText(t *testing.T) {
+
+	body := []byte(`
+		<html>
+		<head>
+			<title>Example Page</title>
+			<meta name="description" content="an example">
+		</head>
+		<body>
+			<nav>skip this nav text</nav>
+			<p>Hello world.</p>
+		</body>
+		</html>
+	`)
+
+	result, err := htmlExtractor{}.Extract(body, &Meta{})
+	if err != nil {
+		t.Fatalf("Extract: %v", err)
+	}
+
+	extracted, ok := result.(*HTMLExtracted)
+	if !ok {
+		t.Fatalf("got %T, want *HTMLExtracted", result)
+	}
+
+	if extracted.Title != "Example Page" {
+		t.Errorf("Title = %q, want %q", extracted.Title, "Example Page")
+	}
+	if extracted.Meta["description"] != "an example" {
+		t.Errorf("Meta[description] = %q, want %q", extracted.Meta["description"], "an example")
+	}
+	if !strings.Contains(extracted.Text, "Hello world.") {
+		t.Errorf("Text = %q, want it to contain %q", extracted.Text, "Hello world.")
+	}
+	if strings.Contains(extracted.Text, "skip this nav text") {
+		t.Errorf("Text = %q, want nav content excluded", extracted.Text)
+	}
+}
+
+func TestFeedExtractorRSS(t *testing.T) {
+
+	body := []byte(`<?xml version="1.0"?>
+	<rss version="2.0">
+		<channel>
+			<title>My RSS Feed</title>
+			<item><title>Post One</title><link>https://example.com/1</link></item>
+			<item><title>Post Two</title><link>https://example.com/2</link></item>
+		</channel>
+	</rss>`)
+
+	result, err := feedExtractor{}.Extract(body, &Meta{})
+	if err != nil {
+		t.Fatalf("Extract: %v", err)
+	}
+
+	extracted := result.(*FeedExtracted)
+	if extracted.Title != "My RSS Feed" {
+		t.Errorf("Title = %q, want %q", extracted.Title, "My RSS Feed")
+	}
+	if len(extracted.Items) != 2 || extracted.Items[0].Link != "https://example.com/1" {
+		t.Errorf("Items = %+v, want 2 items starting with https://example.com/1", extracted.Items)
+	}
+}
+
+func TestFeedExtractorAtom(t *testing.T) {
+
+	body := []byte(`<?xml version="1.0"?>
+	<feed xmlns="http://www.w3.org/2005/Atom">
+		<title>My Atom Feed</title>
+		<entry>
+			<title>Entry One</title>
+			<link href="https://example.com/entry-1"/>
+		</entry>
+	</feed>`)
+
+	result, err := feedExtractor{}.Extract(body, &Meta{})
+	if err != nil {
+		t.Fatalf("Extract: %v", err)
+	}
+
+	extracted := result.(*FeedExtracted)
+	if extracted.Title != "My Atom Feed" {
+		t.Errorf("Title = %q, want %q", extracted.Title, "My Atom Feed")
+	}
+	if len(extracted.Items) != 1 || extracted.Items[0].Link != "https://example.com/entry-1" {
+		t.Errorf("Items = %+v, want 1 entry linking to https://example.com/entry-1", extracted.Items)
+	}
+}
+
+func TestSitemapExtractor(t *testing.T) {
+
+	body := []byte(`<?xml version="1.0"?>
+	<urlset xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">
+		<url><loc>https://example.com/a</loc></url>
+		<url><loc>https://example.com/b</loc></url>
+	</urlset>`)
+
+	result, err := sitemapExtractor{}.Extract(body, &Meta{})
+	if err != nil {
+		t.Fatalf("Extract: %v", err)
+	}
+
+	extracted := result.(*SitemapExtracted)
+	got := append([]string(nil), extracted.URLs...)
+	sort.Strings(got)
+
+	want := []string{"https://example.com/a", "https://example.com/b"}
+	if len(got) != len(want) || got[0] != want[0] || got[1] != want[1] {
+		t.Errorf("URLs = %v, want %v", got, want)
+	}
+}
+
+func TestJSONDepth(t *testing.T) {
+
+	cases := []struct {
+		name string
+		doc  interface{}
+		want int
+	}{
+		{"scalar", "flat", 0},
+		{"flat object", map[string]interface{}{"a": 1}, 1},
+		{"nested object", map[string]interface{}{"a": map[string]interface{}{"b": 1}}, 2},
+		{"nested array", []interface{}{[]interface{}{1}}, 2},
+	}
+
+	for _, c := range cases {
+		if got := jsonDepth(c.doc, 0); got != c.want {
+			t.Errorf("%s: jsonDepth = %d, want %d", c.name, got, c.want)
+		}
+	}
+}
@@ -3,6 +3,8 @@ package main
 import (
 	"bytes"
 	"context"
+	"encoding/json"
+	"fmt"
 	"io"
 	"io/ioutil"
 	"log"
@@ -15,53 +17,151 @@ import (
 	"golang.org/x/net/html"
 )
 
-const (
+// RequestTimeout and Workers are configurable at startup via flags
+// or environment variables; see main().
+var (
 	RequestTimeout = time.Second * 60
 	Workers        = 8
 )
 
+// jobRun tracks a job's in-flight run: cancel interrupts it, and done
+// is closed once every per-URL goroutine has actually exited, so
+// deleteJob can wait for the run to stop touching job before removing
+// it from the store.
+type jobRun struct {
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
 type queueRequest struct {
-	req  string
-	resp chan *ResponseItem
+	ctx        context.Context
+	req        string
+	extractors []string
+	auth       map[string]AuthConfig
+	timeout    time.Duration
+	resp       chan *ResponseItem
 }
 
 type fetcherServer struct {
 	queue chan *queueRequest
 	done  chan bool
+
+	// store persists jobs submitted to /fetch so they survive a
+	// restart; runs tracks the per-job cancel func and completion
+	// signal so DELETE /jobs/{id} can interrupt in-flight fetches and
+	// wait for them to actually exit before deleting the job.
+	store JobStore
+	mu    sync.Mutex
+	runs  map[string]*jobRun
+
+	// extractors is the registry of named Extractors a Request may
+	// opt a URL into, beyond the default tag-count behaviour.
+	extractors *extractorRegistry
+
+	// closing is closed by Shutdown to stop accepting new
+	// queueRequests; pending tracks requests that were accepted and
+	// are still queued or being worked, so Shutdown can wait for
+	// them to drain. shutdownCtx/shutdownCancel give every worker a
+	// context it can watch to abort an in-flight client.Do once the
+	// shutdown grace period elapses.
+	closing        chan struct{}
+	closeOnce      sync.Once
+	pending        sync.WaitGroup
+	workerWg       sync.WaitGroup
+	shutdownCtx    context.Context
+	shutdownCancel context.CancelFunc
 }
 
-func newQueueRequest(url string) *queueRequest {
+func newQueueRequestCtx(ctx context.Context, url string, extractors []string, auth map[string]AuthConfig, timeout time.Duration) *queueRequest {
 	return &queueRequest{
-		req:  url,
-		resp: make(chan *ResponseItem),
+		ctx:        ctx,
+		req:        url,
+		extractors: extractors,
+		auth:       auth,
+		timeout:    timeout,
+		resp:       make(chan *ResponseItem),
 	}
 }
 
+// do enqueues r and blocks for its result. It refuses new work once
+// the fetcher is shutting down.
 func (r *queueRequest) do(fs *fetcherServer) *ResponseItem {
+
+	select {
+	case <-fs.closing:
+		return &ResponseItem{
+			URL: r.req,
+			Meta: Meta{
+				Status: http.StatusServiceUnavailable,
+				Error:  "fetcher is shutting down",
+			},
+		}
+	default:
+	}
+
+	fs.pending.Add(1)
+	defer fs.pending.Done()
+
 	fs.queue <- r
 	return <-r.resp
 }
 
-// create new fetcher backend
-func newFetcher() (*fetcherServer, error) {
+// create new fetcher backend, backed by store for job persistence.
+// Any job left in-flight by a previous run is requeued immediately.
+func newFetcher(store JobStore) (*fetcherServer, error) {
 
 	fs := &fetcherServer{
-		queue: make(chan *queueRequest, 64),
-		done:  make(chan bool, Workers),
+		queue:      make(chan *queueRequest, 64),
+		done:       make(chan bool, Workers),
+		store:      store,
+		runs:       make(map[string]*jobRun),
+		extractors: defaultExtractorRegistry(),
+		closing:    make(chan struct{}),
 	}
+	fs.shutdownCtx, fs.shutdownCancel = context.WithCancel(context.Background())
+
+	fs.workerWg.Add(Workers)
 	for i := 0; i < Workers; i++ {
 		go fs.worker()
 	}
+
+	fs.recoverJobs()
+
 	return fs, nil
 }
 
-func (fs *fetcherServer) stop() {
+// Shutdown stops accepting new queueRequests, waits for whatever was
+// already queued or in-flight to drain, and then tells every worker
+// to exit. If ctx fires before the drain completes, in-flight
+// client.Do calls are cancelled via shutdownCtx so the drain can
+// still finish.
+func (fs *fetcherServer) Shutdown(ctx context.Context) error {
+
+	fs.closeOnce.Do(func() { close(fs.closing) })
+
+	drained := make(chan struct{})
+	go func() {
+		fs.pending.Wait()
+		close(drained)
+	}()
+
+	select {
+	case <-drained:
+	case <-ctx.Done():
+		fs.shutdownCancel()
+		<-drained
+	}
+
 	for i := 0; i < Workers; i++ {
 		fs.done <- true
 	}
+	fs.workerWg.Wait()
+
+	return ctx.Err()
 }
 
-// GIN handler
+// GIN handler: persists the batch as a job and returns immediately;
+// progress and the eventual result are fetched via /jobs/{id}.
 func (fs *fetcherServer) handle(c *gin.Context) {
 
 	var request Request
@@ -73,79 +173,263 @@ func (fs *fetcherServer) handle(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{
 			"error": err.Error(),
 		})
+		return
 	}
 
-	// call handler
-	result, err := fs.do(request)
-	if err != nil {
-		log.Println("Unrecoverable error while fetching the request: %v", err)
+	job := newJob(request)
+
+	if err := fs.store.Create(job); err != nil {
+		log.Println("Error persisting job: %v", err)
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error": err.Error(),
 		})
+		return
 	}
 
-	c.JSON(http.StatusOK, result)
+	fs.runJob(job)
+
+	c.Header("Location", "/jobs/"+job.ID)
+	c.JSON(http.StatusAccepted, gin.H{"id": job.ID})
 }
 
-func (fs *fetcherServer) do(urls []string) (*Response, error) {
+// getJob is the GIN handler for GET /jobs/{id}.
+func (fs *fetcherServer) getJob(c *gin.Context) {
 
-	var (
-		result = make(chan *ResponseItem, len(urls))
-		wg     sync.WaitGroup
-	)
+	job, err := fs.store.Get(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	if job.Done {
+		c.JSON(http.StatusOK, job.toResponse())
+		return
+	}
+
+	c.JSON(http.StatusOK, job)
+}
+
+// streamJob is the GIN handler for GET /jobs/{id}/stream: it pushes
+// the job's progressive state as server-sent events until the job
+// finishes or the client disconnects.
+func (fs *fetcherServer) streamJob(c *gin.Context) {
+
+	id := c.Param("id")
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+
+	ticker := time.NewTicker(500 * time.Millisecond)
+	defer ticker.Stop()
 
-	wg.Add(len(urls))
+	for {
+		select {
+		case <-c.Request.Context().Done():
+			return
+
+		case <-ticker.C:
+			job, err := fs.store.Get(id)
+			if err != nil {
+				return
+			}
+
+			data, err := json.Marshal(job)
+			if err != nil {
+				return
+			}
 
-	for _, param := range urls {
-		go func(url string) {
-			// url is passed as a parameter to create
-			// a copy from the loop one
-			resp := newQueueRequest(url).do(fs)
-			result <- resp
+			fmt.Fprintf(c.Writer, "data: %s\n\n", data)
+			c.Writer.Flush()
 
-			wg.Done()
-		}(param)
+			if job.Done {
+				return
+			}
+		}
 	}
+}
+
+// deleteJob is the GIN handler for DELETE /jobs/{id}: it cancels the
+// job if still in-flight, waits for its goroutines to actually exit
+// so none of them can resurrect the job with a late store.Save, and
+// then removes it from the store.
+func (fs *fetcherServer) deleteJob(c *gin.Context) {
+
+	id := c.Param("id")
+
+	fs.mu.Lock()
+	run, ok := fs.runs[id]
+	fs.mu.Unlock()
+
+	if ok {
+		run.cancel()
+		<-run.done
+	}
+
+	if err := fs.store.Delete(id); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// runJob dispatches every URL in job through the worker pool under a
+// cancellable per-job context, persisting progress as each fetch
+// completes. DELETE /jobs/{id} cancels this context so client.Do is
+// interrupted mid-fetch.
+func (fs *fetcherServer) runJob(job *Job) {
+
+	ctx, cancel := context.WithCancel(context.Background())
+	run := &jobRun{cancel: cancel, done: make(chan struct{})}
+
+	fs.mu.Lock()
+	fs.runs[job.ID] = run
+
+	// Status/Results come back nil when a job is unmarshaled from the
+	// store with no entries yet recorded (Results has
+	// json:"omitempty", and an empty map marshals the same as a nil
+	// one): the first write below would panic on a nil map. Also skip
+	// any URL already JobDone, so a job recovered after a crash
+	// resumes instead of re-fetching everything from scratch.
+	if job.Status == nil {
+		job.Status = make(map[string]JobStatus, len(job.URLs))
+	}
+	if job.Results == nil {
+		job.Results = make(map[string]*ResponseItem, len(job.URLs))
+	}
+	pending := make([]string, 0, len(job.URLs))
+	for _, u := range job.URLs {
+		if job.Status[u] != JobDone {
+			pending = append(pending, u)
+		}
+	}
+	fs.mu.Unlock()
 
-	// close channel on completion
 	go func() {
+		defer func() {
+			fs.mu.Lock()
+			delete(fs.runs, job.ID)
+			fs.mu.Unlock()
+			cancel()
+			close(run.done)
+		}()
+
+		var wg sync.WaitGroup
+		wg.Add(len(pending))
+
+		for _, param := range pending {
+			go func(url string) {
+				defer wg.Done()
+
+				fs.setJobStatus(job, url, JobInProgress)
+
+				resp := newQueueRequestCtx(ctx, url, job.Extractors[url], job.Auth, time.Duration(job.Timeout)).do(fs)
+
+				fs.mu.Lock()
+				if ctx.Err() != nil {
+					job.Status[url] = JobError
+				} else {
+					job.Results[url] = resp
+					job.Status[url] = JobDone
+				}
+				snapshot := job.snapshot()
+				fs.mu.Unlock()
+
+				if err := fs.store.Save(snapshot); err != nil {
+					log.Println("Error saving job progress: %v", err)
+				}
+			}(param)
+		}
+
 		wg.Wait()
-		close(result)
+
+		fs.mu.Lock()
+		job.Done = true
+		snapshot := job.snapshot()
+		fs.mu.Unlock()
+
+		if err := fs.store.Save(snapshot); err != nil {
+			log.Println("Error saving finished job: %v", err)
+		}
 	}()
+}
 
-	var output = Response([]*ResponseItem{})
-	for item := range result {
-		output = append(output, item)
+func (fs *fetcherServer) setJobStatus(job *Job, url string, status JobStatus) {
+	fs.mu.Lock()
+	job.Status[url] = status
+	snapshot := job.snapshot()
+	fs.mu.Unlock()
+
+	if err := fs.store.Save(snapshot); err != nil {
+		log.Println("Error saving job status: %v", err)
 	}
+}
 
-	return &output, nil
+// recoverJobs requeues every job left in-flight by a previous run.
+func (fs *fetcherServer) recoverJobs() {
+
+	jobs, err := fs.store.List()
+	if err != nil {
+		log.Println("Error listing persisted jobs: %v", err)
+		return
+	}
+
+	for _, job := range jobs {
+		if job.Done {
+			continue
+		}
+		fs.runJob(job)
+	}
 }
 
 func (fs *fetcherServer) worker() {
+	defer fs.workerWg.Done()
 
-	select {
-	case in := <-fs.queue:
-		// do the fetching
-		res, err := fs.work(in.req)
-		if err != nil {
-			// error feedback is wanted
-			res = &ResponseItem{
-				URL: in.req,
-				Meta: Meta{
-					Status: http.StatusInternalServerError,
-					Error:  err.Error(),
-				},
-			}
+	for {
+		select {
+		case in := <-fs.queue:
+			fs.handle1(in)
+
+		case <-fs.done:
+			return
 		}
+	}
+}
 
-		in.resp <- res
+// handle1 runs a single queueRequest to completion. The context it
+// fetches under is derived from both the request's own context and
+// fs.shutdownCtx, so a Shutdown() that times out interrupts whatever
+// this worker is doing mid-fetch.
+func (fs *fetcherServer) handle1(in *queueRequest) {
 
-	case <-fs.done:
-		return
+	ctx, cancel := context.WithCancel(in.ctx)
+	defer cancel()
+
+	go func() {
+		select {
+		case <-fs.shutdownCtx.Done():
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	res, err := fs.work(ctx, in.req, in.extractors, in.auth, in.timeout)
+	if err != nil {
+		// error feedback is wanted
+		res = &ResponseItem{
+			URL: in.req,
+			Meta: Meta{
+				Status: http.StatusInternalServerError,
+				Error:  err.Error(),
+			},
+		}
 	}
+
+	in.resp <- res
 }
 
-func (fs *fetcherServer) work(url string) (*ResponseItem, error) {
+func (fs *fetcherServer) work(parent context.Context, url string, extractors []string, auth map[string]AuthConfig, timeout time.Duration) (*ResponseItem, error) {
 
 	// do GET with timeout
 	req, err := http.NewRequest("GET", url, nil)
@@ -153,10 +437,15 @@ func (fs *fetcherServer) work(url string) (*ResponseItem, error) {
 		return nil, err
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), RequestTimeout)
+	if timeout <= 0 {
+		timeout = RequestTimeout
+	}
+
+	ctx, cancel := context.WithTimeout(parent, timeout)
 	defer cancel()
 
-	client := &http.Client{}
+	transport := newAuthTransport(auth)
+	client := &http.Client{Transport: transport}
 
 	resp, err := client.Do(req.WithContext(ctx))
 	if err != nil {
@@ -166,7 +455,8 @@ func (fs *fetcherServer) work(url string) (*ResponseItem, error) {
 	var result = ResponseItem{
 		URL: url,
 		Meta: Meta{
-			Status: resp.StatusCode,
+			Status:     resp.StatusCode,
+			AuthScheme: transport.scheme,
 		},
 	}
 
@@ -187,31 +477,48 @@ func (fs *fetcherServer) work(url string) (*ResponseItem, error) {
 
 	result.Meta.ContentLength = len(body)
 
-	// abort if content is empty or not html
-	if result.Meta.ContentLength == 0 ||
-		!strings.HasPrefix(result.Meta.ContentType, "text/html") {
-
+	// abort if content is empty
+	if result.Meta.ContentLength == 0 {
 		return &result, nil
 	}
 
-	// count && fill-in tags
-	tags, err := countTags(body)
-	if err != nil {
-		// just HTML errors; ignore
-		log.Println("HTML parse error: %v", err)
-	} else {
-		result.Elements = tags
+	if extracted := fs.extractors.run(extractors, body, &result.Meta); len(extracted) > 0 {
+		result.Extracted = extracted
+	}
+
+	// preserve the default behaviour of tag-counting and link
+	// collection on HTML bodies, regardless of which extractors
+	// (if any) were opted into
+	if strings.HasPrefix(result.Meta.ContentType, "text/html") {
+		tags, links, err := countTags(body)
+		if err != nil {
+			// just HTML errors; ignore
+			log.Println("HTML parse error: %v", err)
+		} else {
+			result.Elements = tags
+			result.Links = links
+		}
 	}
 
 	return &result, nil
 }
 
-// count all html-tags in input document
+// linkAttr maps the tag names whose links feed the crawler frontier
+// to the attribute that carries the URL.
+var linkAttr = map[string]string{
+	"a":      "href",
+	"link":   "href",
+	"iframe": "src",
+}
+
+// count all html-tags in input document, also collecting outbound
+// links (href/src of a, link and iframe tags) for the crawler.
 //  <p>lol</p> is one p element
-func countTags(body []byte) ([]Element, error) {
+func countTags(body []byte) ([]Element, []string, error) {
 
 	var (
 		counts = map[string]int{}
+		links  []string
 		reader = bytes.NewBuffer(body)
 		z      = html.NewTokenizer(reader)
 	)
@@ -221,16 +528,27 @@ func countTags(body []byte) ([]Element, error) {
 		case html.ErrorToken:
 			if z.Err() == io.EOF {
 				// this is the return-point
-				return encodeTags(counts), nil
+				return encodeTags(counts), links, nil
 			}
 
 			// any other err is unexpected
 			log.Printf("html token err: %v", z.Err())
-			return nil, z.Err()
+			return nil, nil, z.Err()
 
 		case html.StartTagToken, html.SelfClosingTagToken:
-			tagName, _ := z.TagName()
-			counts[string(tagName)] += 1
+			tagName, hasAttr := z.TagName()
+			name := string(tagName)
+			counts[name] += 1
+
+			attr, wantsLink := linkAttr[name]
+			for hasAttr && wantsLink {
+				var key, val []byte
+				key, val, hasAttr = z.TagAttr()
+				if string(key) == attr {
+					links = append(links, string(val))
+					break
+				}
+			}
 		}
 	}
 
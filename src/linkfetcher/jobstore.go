@@ -0,0 +1,181 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	bolt "go.etcd.io/bbolt"
+)
+
+// JobStatus is the lifecycle state of a single URL within a job.
+type JobStatus string
+
+const (
+	JobPending    JobStatus = "pending"
+	JobInProgress JobStatus = "in_progress"
+	JobDone       JobStatus = "done"
+	JobError      JobStatus = "error"
+)
+
+// Job is a persisted batch fetch: the URLs it covers, and the
+// per-URL status/result recorded as the worker pool completes them.
+type Job struct {
+	ID         string                   `json:"id"`
+	URLs       []string                 `json:"urls"`
+	Extractors map[string][]string      `json:"extractors,omitempty"`
+	Auth       map[string]AuthConfig    `json:"auth,omitempty"`
+	Timeout    Duration                 `json:"timeout,omitempty"`
+	Status     map[string]JobStatus     `json:"status"`
+	Results    map[string]*ResponseItem `json:"results,omitempty"`
+	Done       bool                     `json:"done"`
+}
+
+func newJob(request Request) *Job {
+	job := &Job{
+		ID:         uuid.New().String(),
+		URLs:       request.URLs,
+		Extractors: request.Extractors,
+		Auth:       request.Auth,
+		Timeout:    request.Timeout,
+		Status:     make(map[string]JobStatus, len(request.URLs)),
+		Results:    make(map[string]*ResponseItem, len(request.URLs)),
+	}
+	for _, u := range job.URLs {
+		job.Status[u] = JobPending
+	}
+	return job
+}
+
+// toResponse collects whatever results are in, in the order URLs
+// were submitted, for callers that only want the final Response.
+func (job *Job) toResponse() *Response {
+	out := make(Response, 0, len(job.URLs))
+	for _, u := range job.URLs {
+		if item, ok := job.Results[u]; ok {
+			out = append(out, item)
+		}
+	}
+	return &out
+}
+
+// snapshot returns a copy of job safe to hand to the store outside of
+// whatever lock protects Status/Results: the fields the worker pool
+// mutates in place (Status, Results) are copied into fresh maps, so a
+// concurrent update to the live job can't race with json.Marshal-ing
+// this copy. Extractors/Auth/URLs are never mutated after newJob, so
+// they're copied by reference.
+func (job *Job) snapshot() *Job {
+
+	cp := *job
+
+	cp.Status = make(map[string]JobStatus, len(job.Status))
+	for k, v := range job.Status {
+		cp.Status[k] = v
+	}
+
+	cp.Results = make(map[string]*ResponseItem, len(job.Results))
+	for k, v := range job.Results {
+		cp.Results[k] = v
+	}
+
+	return &cp
+}
+
+// JobStore persists jobs so a submitted batch of URLs survives a
+// process restart.
+type JobStore interface {
+	Create(job *Job) error
+	Save(job *Job) error
+	Get(id string) (*Job, error)
+	Delete(id string) error
+	// List returns every persisted job; newFetcher uses it on
+	// startup to requeue jobs left in-flight by a previous run.
+	List() ([]*Job, error)
+}
+
+var jobsBucket = []byte("jobs")
+
+// boltJobStore is a JobStore backed by a BoltDB (bbolt) file, so a
+// job survives between process restarts without any external
+// dependency beyond the local filesystem.
+type boltJobStore struct {
+	db *bolt.DB
+}
+
+func newBoltJobStore(path string) (JobStore, error) {
+
+	db, err := bolt.Open(path, 0600, &bolt.Options{Timeout: time.Second})
+	if err != nil {
+		return nil, err
+	}
+
+	err = db.Update(func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists(jobsBucket)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &boltJobStore{db: db}, nil
+}
+
+func (s *boltJobStore) Create(job *Job) error {
+	return s.Save(job)
+}
+
+func (s *boltJobStore) Save(job *Job) error {
+
+	data, err := json.Marshal(job)
+	if err != nil {
+		return err
+	}
+
+	return s.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(jobsBucket).Put([]byte(job.ID), data)
+	})
+}
+
+func (s *boltJobStore) Get(id string) (*Job, error) {
+
+	var job Job
+
+	err := s.db.View(func(tx *bolt.Tx) error {
+		data := tx.Bucket(jobsBucket).Get([]byte(id))
+		if data == nil {
+			return fmt.Errorf("job %s not found", id)
+		}
+		return json.Unmarshal(data, &job)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &job, nil
+}
+
+func (s *boltJobStore) Delete(id string) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(jobsBucket).Delete([]byte(id))
+	})
+}
+
+func (s *boltJobStore) List() ([]*Job, error) {
+
+	var jobs []*Job
+
+	err := s.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(jobsBucket).ForEach(func(k, v []byte) error {
+			var job Job
+			if err := json.Unmarshal(v, &job); err != nil {
+				return err
+			}
+			jobs = append(jobs, &job)
+			return nil
+		})
+	})
+
+	return jobs, err
+}
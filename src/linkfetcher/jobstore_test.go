@@ -0,0 +1,207 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// waitForJobDone polls the store until job.Done or the deadline
+// passes, for asserting on runJob's async completion.
+func waitForJobDone(t *testing.T, store JobStore, id string) *Job {
+	t.Helper()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		job, err := store.Get(id)
+		if err != nil {
+			t.Fatalf("Get: %v", err)
+		}
+		if job.Done {
+			return job
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	t.Fatalf("job %s did not finish within the deadline", id)
+	return nil
+}
+
+func newTestStore(t *testing.T) JobStore {
+	t.Helper()
+
+	store, err := newBoltJobStore(filepath.Join(t.TempDir(), "fetcher.db"))
+	if err != nil {
+		t.Fatalf("newBoltJobStore: %v", err)
+	}
+
+	return store
+}
+
+func TestBoltJobStoreRoundTrip(t *testing.T) {
+
+	store := newTestStore(t)
+
+	job := newJob(Request{URLs: []string{"https://example.com"}})
+
+	if err := store.Create(job); err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+
+	got, err := store.Get(job.ID)
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	if got.ID != job.ID || got.Status["https://example.com"] != JobPending {
+		t.Fatalf("Get returned unexpected job: %+v", got)
+	}
+
+	job.Status["https://example.com"] = JobDone
+	job.Results["https://example.com"] = &ResponseItem{URL: "https://example.com"}
+	if err := store.Save(job); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+
+	got, err = store.Get(job.ID)
+	if err != nil {
+		t.Fatalf("Get after Save: %v", err)
+	}
+	if got.Status["https://example.com"] != JobDone {
+		t.Fatalf("Save did not persist updated status: %+v", got)
+	}
+
+	jobs, err := store.List()
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	if len(jobs) != 1 || jobs[0].ID != job.ID {
+		t.Fatalf("List returned %+v, want single job %s", jobs, job.ID)
+	}
+
+	if err := store.Delete(job.ID); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if _, err := store.Get(job.ID); err == nil {
+		t.Fatalf("Get succeeded after Delete")
+	}
+}
+
+func TestRecoverJobsRequeuesUnfinishedOnly(t *testing.T) {
+
+	store := newTestStore(t)
+
+	pending := newJob(Request{URLs: []string{"http://127.0.0.1:1/pending"}})
+	if err := store.Create(pending); err != nil {
+		t.Fatalf("Create pending: %v", err)
+	}
+
+	done := newJob(Request{URLs: []string{"https://example.com/done"}})
+	done.Done = true
+	if err := store.Create(done); err != nil {
+		t.Fatalf("Create done: %v", err)
+	}
+
+	fs, err := newFetcher(store)
+	if err != nil {
+		t.Fatalf("newFetcher: %v", err)
+	}
+
+	fs.mu.Lock()
+	_, pendingRequeued := fs.runs[pending.ID]
+	_, doneRequeued := fs.runs[done.ID]
+	fs.mu.Unlock()
+
+	if !pendingRequeued {
+		t.Fatalf("recoverJobs did not requeue the unfinished job")
+	}
+	if doneRequeued {
+		t.Fatalf("recoverJobs requeued an already-done job")
+	}
+}
+
+// TestRunJobInitializesNilMaps guards against a regression where a job
+// unmarshaled from the store with no entries recorded yet (Status and
+// Results both come back nil, since Results has json:"omitempty" and
+// an empty map marshals the same as a nil one) panicked on the first
+// write to job.Results in the per-URL goroutine.
+func TestRunJobInitializesNilMaps(t *testing.T) {
+
+	store := newTestStore(t)
+
+	job := &Job{
+		ID:   "nil-maps",
+		URLs: []string{"http://127.0.0.1:1/x"},
+	}
+	if err := store.Create(job); err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+
+	fs, err := newFetcher(store)
+	if err != nil {
+		t.Fatalf("newFetcher: %v", err)
+	}
+
+	fs.runJob(job)
+
+	done := waitForJobDone(t, store, job.ID)
+	if done.Status["http://127.0.0.1:1/x"] == "" {
+		t.Fatalf("URL was never recorded: %+v", done)
+	}
+}
+
+// TestRunJobSkipsAlreadyDoneURLs guards against a regression where
+// recovering a job after a crash re-fetched every URL from scratch,
+// discarding already-succeeded results instead of resuming.
+func TestRunJobSkipsAlreadyDoneURLs(t *testing.T) {
+
+	store := newTestStore(t)
+
+	finished := &ResponseItem{URL: "https://example.com/done", Meta: Meta{Status: 200}}
+	job := &Job{
+		ID:   "partial",
+		URLs: []string{"https://example.com/done", "http://127.0.0.1:1/pending"},
+		Status: map[string]JobStatus{
+			"https://example.com/done": JobDone,
+		},
+		Results: map[string]*ResponseItem{
+			"https://example.com/done": finished,
+		},
+	}
+	if err := store.Create(job); err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+
+	fs, err := newFetcher(store)
+	if err != nil {
+		t.Fatalf("newFetcher: %v", err)
+	}
+
+	fs.runJob(job)
+
+	done := waitForJobDone(t, store, job.ID)
+
+	got := done.Results["https://example.com/done"]
+	if got == nil || got.Meta.Status != 200 {
+		t.Fatalf("already-done URL's result was overwritten: %+v", got)
+	}
+	if done.Status["http://127.0.0.1:1/pending"] == "" {
+		t.Fatalf("pending URL was never (re-)fetched: %+v", done)
+	}
+}
+
+func TestJobSnapshotIsolatesMutation(t *testing.T) {
+
+	job := newJob(Request{URLs: []string{"https://example.com"}})
+
+	snap := job.snapshot()
+
+	job.Status["https://example.com"] = JobDone
+	job.Results["https://example.com"] = &ResponseItem{URL: "https://example.com"}
+
+	if snap.Status["https://example.com"] != JobPending {
+		t.Fatalf("mutating job after snapshot changed the snapshot's Status map")
+	}
+	if _, ok := snap.Results["https://example.com"]; ok {
+		t.Fatalf("mutating job after snapshot changed the snapshot's Results map")
+	}
+}
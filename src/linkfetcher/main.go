@@ -0,0 +1,133 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func main() {
+
+	var (
+		addr            = flag.String("addr", envOr("FETCHER_ADDR", ":8080"), "address to listen on")
+		dbPath          = flag.String("db", envOr("FETCHER_DB", "fetcher.db"), "path to the job store database")
+		shutdownTimeout = flag.Duration("shutdown-timeout", envDurationOr("FETCHER_SHUTDOWN_TIMEOUT", 30*time.Second), "grace period for draining in-flight fetches on shutdown")
+	)
+	flag.IntVar(&Workers, "workers", envIntOr("FETCHER_WORKERS", Workers), "number of fetch workers")
+	flag.DurationVar(&RequestTimeout, "request-timeout", envDurationOr("FETCHER_REQUEST_TIMEOUT", RequestTimeout), "default per-fetch timeout, overridable per Request")
+	var authCommands authCommandFlag
+	flag.Var(&authCommands, "auth-command", "register a \"command\" credential helper as name=argv0,arg1,arg2 (repeatable); only names registered this way are reachable from a request's auth.name")
+	flag.Parse()
+
+	for name, argv := range authCommands {
+		registerCommandProvider(name, argv)
+	}
+
+	store, err := newBoltJobStore(*dbPath)
+	if err != nil {
+		log.Fatalf("Error opening job store: %v", err)
+	}
+
+	fs, err := newFetcher(store)
+	if err != nil {
+		log.Fatalf("Error starting fetcher: %v", err)
+	}
+
+	router := gin.Default()
+	router.POST("/fetch", fs.handle)
+	router.POST("/crawl", fs.crawlHandle)
+	router.GET("/jobs/:id", fs.getJob)
+	router.GET("/jobs/:id/stream", fs.streamJob)
+	router.DELETE("/jobs/:id", fs.deleteJob)
+	fs.registerUI(router)
+
+	server := &http.Server{Addr: *addr, Handler: router}
+
+	go func() {
+		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			log.Fatalf("Error serving: %v", err)
+		}
+	}()
+
+	stop := make(chan os.Signal, 1)
+	signal.Notify(stop, os.Interrupt, syscall.SIGTERM)
+	<-stop
+
+	log.Println("Shutting down...")
+
+	ctx, cancel := context.WithTimeout(context.Background(), *shutdownTimeout)
+	defer cancel()
+
+	if err := server.Shutdown(ctx); err != nil {
+		log.Printf("Error shutting down HTTP server: %v", err)
+	}
+
+	if err := fs.Shutdown(ctx); err != nil {
+		log.Printf("Error draining in-flight fetches: %v", err)
+	}
+}
+
+// authCommandFlag collects -auth-command flags into a name -> argv map.
+// Each occurrence is "name=argv0,arg1,arg2"; this is the only way a
+// "command" CredentialProvider's argv can be set, since accepting it
+// from request JSON would let any caller run arbitrary commands.
+type authCommandFlag map[string][]string
+
+func (f *authCommandFlag) String() string {
+	return fmt.Sprintf("%v", map[string][]string(*f))
+}
+
+func (f *authCommandFlag) Set(value string) error {
+
+	name, argvCSV, ok := strings.Cut(value, "=")
+	if !ok || name == "" {
+		return fmt.Errorf("auth-command %q: expected name=argv0,arg1,...", value)
+	}
+
+	argv := strings.Split(argvCSV, ",")
+	if len(argv) == 0 || argv[0] == "" {
+		return fmt.Errorf("auth-command %q: empty command", value)
+	}
+
+	if *f == nil {
+		*f = authCommandFlag{}
+	}
+	(*f)[name] = argv
+
+	return nil
+}
+
+func envOr(key, def string) string {
+	if v, ok := os.LookupEnv(key); ok {
+		return v
+	}
+	return def
+}
+
+func envIntOr(key string, def int) int {
+	if v, ok := os.LookupEnv(key); ok {
+		if n, err := strconv.Atoi(v); err == nil {
+			return n
+		}
+	}
+	return def
+}
+
+func envDurationOr(key string, def time.Duration) time.Duration {
+	if v, ok := os.LookupEnv(key); ok {
+		if d, err := time.ParseDuration(v); err == nil {
+			return d
+		}
+	}
+	return def
+}
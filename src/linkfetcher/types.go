@@ -0,0 +1,118 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// Duration wraps time.Duration so request bodies can give a timeout
+// as a human string ("30s", "2m") parsed the same way as the
+// -request-timeout flag/FETCHER_REQUEST_TIMEOUT env var, instead of
+// only as a raw count of nanoseconds.
+type Duration time.Duration
+
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
+}
+
+func (d *Duration) UnmarshalJSON(data []byte) error {
+
+	var v interface{}
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+
+	switch val := v.(type) {
+	case string:
+		parsed, err := time.ParseDuration(val)
+		if err != nil {
+			return err
+		}
+		*d = Duration(parsed)
+	case float64:
+		*d = Duration(val)
+	default:
+		return fmt.Errorf("duration must be a string (e.g. \"30s\") or a number of nanoseconds, got %T", v)
+	}
+
+	return nil
+}
+
+// Request is a batch of URLs to fetch, with an optional per-URL list
+// of named extractors (see Extractor) to run beyond the default
+// tag-count behaviour, optional per-host credentials to use when an
+// origin challenges a fetch with 401 (see CredentialProvider), and an
+// optional Timeout overriding the server's default RequestTimeout for
+// every URL in this batch.
+type Request struct {
+	URLs       []string              `json:"urls"`
+	Extractors map[string][]string   `json:"extractors,omitempty"`
+	Auth       map[string]AuthConfig `json:"auth,omitempty"`
+	Timeout    Duration              `json:"timeout,omitempty"`
+}
+
+// AuthConfig configures the CredentialProvider used for a host
+// pattern (an exact host, or "*.example.com").
+type AuthConfig struct {
+	Type string `json:"type"`
+
+	// basic
+	Username string `json:"username,omitempty"`
+	Password string `json:"password,omitempty"`
+
+	// bearer
+	Token string `json:"token,omitempty"`
+
+	// oauth2 (client-credentials grant)
+	ClientID     string   `json:"client_id,omitempty"`
+	ClientSecret string   `json:"client_secret,omitempty"`
+	TokenURL     string   `json:"token_url,omitempty"`
+	Scopes       []string `json:"scopes,omitempty"`
+
+	// command: looks up a named external credential helper that the
+	// operator registered at startup (see main.go's -auth-command
+	// flag). The argv itself is never accepted here — only a name —
+	// since running an argv supplied by the request body would let
+	// any caller execute arbitrary commands on the host.
+	Name string `json:"name,omitempty"`
+}
+
+// Response is the result of fetching a Request.
+type Response []*ResponseItem
+
+// ResponseItem describes the outcome of fetching a single URL.
+type ResponseItem struct {
+	URL      string    `json:"url"`
+	Meta     Meta      `json:"meta"`
+	Elements []Element `json:"elements,omitempty"`
+
+	// Extracted holds the result of each opted-in Extractor, keyed
+	// by its Name().
+	Extracted map[string]interface{} `json:"extracted,omitempty"`
+
+	// Links holds outbound URLs discovered while counting tags
+	// (href/src of a, link and iframe). It feeds the crawler's
+	// frontier and is not part of the public response.
+	Links []string `json:"-"`
+}
+
+// Meta carries the HTTP-level facts about a fetch.
+type Meta struct {
+	Status        int    `json:"status"`
+	Error         string `json:"error,omitempty"`
+	ContentType   string `json:"content_type,omitempty"`
+	ContentLength int    `json:"content_length,omitempty"`
+
+	// AuthScheme is set to the WWW-Authenticate scheme ("Basic",
+	// "Bearer", ...) when the origin answered 401 and the fetch was
+	// retried with credentials. It is set whatever the retry's own
+	// status was; Status holds that status.
+	AuthScheme string `json:"auth_scheme,omitempty"`
+}
+
+// Element is a single HTML tag with its occurrence count.
+type Element struct {
+	TagName string `json:"tag_name"`
+	Count   int    `json:"count"`
+}
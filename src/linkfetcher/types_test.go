@@ -0,0 +1,40 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestDurationUnmarshalsHumanString(t *testing.T) {
+
+	var req Request
+	if err := json.Unmarshal([]byte(`{"urls":["https://example.com"],"timeout":"30s"}`), &req); err != nil {
+		t.Fatalf("Unmarshal: %v", err)
+	}
+
+	if time.Duration(req.Timeout) != 30*time.Second {
+		t.Errorf("Timeout = %v, want 30s", time.Duration(req.Timeout))
+	}
+}
+
+func TestDurationUnmarshalsNanoseconds(t *testing.T) {
+
+	var req Request
+	if err := json.Unmarshal([]byte(`{"urls":["https://example.com"],"timeout":30000000000}`), &req); err != nil {
+		t.Fatalf("Unmarshal: %v", err)
+	}
+
+	if time.Duration(req.Timeout) != 30*time.Second {
+		t.Errorf("Timeout = %v, want 30s", time.Duration(req.Timeout))
+	}
+}
+
+func TestDurationUnmarshalRejectsGarbage(t *testing.T) {
+
+	var req Request
+	err := json.Unmarshal([]byte(`{"urls":["https://example.com"],"timeout":"not-a-duration"}`), &req)
+	if err == nil {
+		t.Fatalf("expected an error for an unparseable duration string")
+	}
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"html/template"
+	"log"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/Masterminds/sprig/v3"
+	"github.com/gin-gonic/gin"
+)
+
+var uiTemplates = template.Must(template.New("ui").Funcs(sprig.FuncMap()).Parse(uiTemplateSource))
+
+// registerUI mounts the HTML interface at /ui: a form to launch a
+// job, a job list, and a per-job detail page. It is a thin client
+// over the same JSON-backed JobStore the API uses, so progress shown
+// here always matches GET /jobs/{id}.
+func (fs *fetcherServer) registerUI(router gin.IRouter) {
+	ui := router.Group("/ui")
+	ui.GET("", fs.uiIndex)
+	ui.POST("/jobs", fs.uiSubmit)
+	ui.GET("/jobs/:id", fs.uiJobDetail)
+	ui.GET("/jobs/:id/stream", fs.streamJob)
+}
+
+func (fs *fetcherServer) uiIndex(c *gin.Context) {
+
+	jobs, err := fs.store.List()
+	if err != nil {
+		c.String(http.StatusInternalServerError, "error listing jobs: %v", err)
+		return
+	}
+
+	sort.Slice(jobs, func(i, j int) bool { return jobs[i].ID > jobs[j].ID })
+
+	renderUI(c, "index", gin.H{"View": "index", "Jobs": jobs})
+}
+
+func (fs *fetcherServer) uiSubmit(c *gin.Context) {
+
+	urls := splitLines(c.PostForm("urls"))
+
+	job := newJob(Request{URLs: urls})
+
+	if err := fs.store.Create(job); err != nil {
+		c.String(http.StatusInternalServerError, "error creating job: %v", err)
+		return
+	}
+
+	fs.runJob(job)
+
+	c.Redirect(http.StatusSeeOther, "/ui/jobs/"+job.ID)
+}
+
+func (fs *fetcherServer) uiJobDetail(c *gin.Context) {
+
+	job, err := fs.store.Get(c.Param("id"))
+	if err != nil {
+		c.String(http.StatusNotFound, "job not found: %v", err)
+		return
+	}
+
+	renderUI(c, "job", gin.H{"View": "job", "Job": job, "Histogram": elementHistogram(job)})
+}
+
+func renderUI(c *gin.Context, name string, data gin.H) {
+	c.Header("Content-Type", "text/html; charset=utf-8")
+	if err := uiTemplates.ExecuteTemplate(c.Writer, name, data); err != nil {
+		log.Println("ui template error: %v", err)
+	}
+}
+
+// splitLines parses the newline-separated URL textarea input from
+// the submit form.
+func splitLines(raw string) []string {
+	var urls []string
+	for _, line := range strings.Split(raw, "\n") {
+		line = strings.TrimSpace(line)
+		if line != "" {
+			urls = append(urls, line)
+		}
+	}
+	return urls
+}
+
+// elementHistogram sums Elements across every fetched URL in a job,
+// for the drill-down bar chart on the job detail page.
+func elementHistogram(job *Job) map[string]int {
+	hist := map[string]int{}
+	for _, item := range job.Results {
+		for _, el := range item.Elements {
+			hist[el.TagName] += el.Count
+		}
+	}
+	return hist
+}
@@ -0,0 +1,122 @@
+package main
+
+// uiTemplateSource holds every html/template the UI renders, as one
+// parse unit so {{template ...}} can share layout between pages.
+const uiTemplateSource = `
+{{define "layout"}}
+<!doctype html>
+<html>
+<head>
+	<meta charset="utf-8">
+	<title>linkfetcher</title>
+	<style>
+		body { font-family: sans-serif; margin: 2em; color: #222; }
+		table { border-collapse: collapse; width: 100%; }
+		th, td { border: 1px solid #ccc; padding: 0.4em 0.6em; text-align: left; }
+		th { cursor: pointer; background: #f2f2f2; }
+		.bar-row { display: flex; align-items: center; margin: 0.2em 0; }
+		.bar-label { width: 8em; }
+		.bar { background: #4a7; height: 1em; }
+		textarea { width: 100%; height: 8em; }
+	</style>
+</head>
+<body>
+	<h1><a href="/ui">linkfetcher</a></h1>
+	{{template "body" .}}
+	<script>
+	function sortableTable(id) {
+		var table = document.getElementById(id);
+		if (!table) return;
+		var headers = table.querySelectorAll("th");
+		headers.forEach(function(th, idx) {
+			th.addEventListener("click", function() {
+				var rows = Array.prototype.slice.call(table.querySelectorAll("tbody tr"));
+				var asc = th.getAttribute("data-asc") !== "true";
+				rows.sort(function(a, b) {
+					var av = a.children[idx].innerText;
+					var bv = b.children[idx].innerText;
+					return asc ? av.localeCompare(bv, undefined, {numeric: true})
+					           : bv.localeCompare(av, undefined, {numeric: true});
+				});
+				rows.forEach(function(r) { table.querySelector("tbody").appendChild(r); });
+				headers.forEach(function(h) { h.removeAttribute("data-asc"); });
+				th.setAttribute("data-asc", asc);
+			});
+		});
+	}
+	</script>
+</body>
+</html>
+{{end}}
+
+{{define "index"}}{{template "layout" .}}{{end}}
+{{define "job"}}{{template "layout" .}}{{end}}
+
+{{define "body"}}{{if eq .View "job"}}{{template "job-body" .}}{{else}}{{template "index-body" .}}{{end}}{{end}}
+
+{{define "index-body"}}
+	<h2>Submit a batch</h2>
+	<form method="post" action="/ui/jobs">
+		<textarea name="urls" placeholder="one URL per line"></textarea>
+		<p><button type="submit">Fetch</button></p>
+	</form>
+
+	<h2>Recent jobs</h2>
+	<table id="jobs">
+		<thead><tr><th>ID</th><th>URLs</th><th>Status</th></tr></thead>
+		<tbody>
+		{{range .Jobs}}
+			<tr>
+				<td><a href="/ui/jobs/{{.ID}}">{{.ID | trunc 8}}</a></td>
+				<td>{{len .URLs}}</td>
+				<td>{{if .Done}}done{{else}}in progress{{end}}</td>
+			</tr>
+		{{end}}
+		</tbody>
+	</table>
+	<script>sortableTable("jobs")</script>
+{{end}}
+
+{{define "job-body"}}
+	<h2>Job {{.Job.ID}}</h2>
+	<p id="job-status">{{if .Job.Done}}done{{else}}in progress{{end}}</p>
+
+	<table id="results">
+		<thead><tr><th>URL</th><th>Status</th><th>Content-Type</th><th>Content-Length</th><th>Top tags</th></tr></thead>
+		<tbody>
+		{{range .Job.URLs}}
+			{{$item := index $.Job.Results .}}
+			<tr>
+				<td>{{.}}</td>
+				<td>{{if $item}}{{$item.Meta.Status}}{{else}}{{index $.Job.Status .}}{{end}}</td>
+				<td>{{if $item}}{{$item.Meta.ContentType}}{{end}}</td>
+				<td>{{if $item}}{{$item.Meta.ContentLength}}{{end}}</td>
+				<td>{{if $item}}{{range $item.Elements}}{{.TagName}}:{{.Count}} {{end}}{{end}}</td>
+			</tr>
+		{{end}}
+		</tbody>
+	</table>
+	<script>sortableTable("results")</script>
+
+	<h3>Element histogram</h3>
+	{{range $tag, $count := .Histogram}}
+		<div class="bar-row">
+			<div class="bar-label">{{$tag}}</div>
+			<div class="bar" style="width: {{mul $count 8}}px"></div>
+			<div>{{$count}}</div>
+		</div>
+	{{end}}
+
+	{{if not .Job.Done}}
+	<script>
+	var src = new EventSource("/ui/jobs/{{.Job.ID}}/stream");
+	src.onmessage = function(ev) {
+		var job = JSON.parse(ev.data);
+		if (job.done) {
+			location.reload();
+		}
+	};
+	</script>
+	{{end}}
+{{end}}
+`
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func newTestUIServer(t *testing.T) (*fetcherServer, *gin.Engine) {
+	t.Helper()
+
+	gin.SetMode(gin.TestMode)
+
+	fs, err := newFetcher(newTestStore(t))
+	if err != nil {
+		t.Fatalf("newFetcher: %v", err)
+	}
+
+	router := gin.New()
+	fs.registerUI(router)
+
+	return fs, router
+}
+
+func TestUIIndexRendersFormOnEmptyJobList(t *testing.T) {
+
+	_, router := newTestUIServer(t)
+
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/ui", nil)
+	router.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("GET /ui: status = %d, want %d", rec.Code, http.StatusOK)
+	}
+	if !strings.Contains(rec.Body.String(), "Submit a batch") {
+		t.Errorf("GET /ui on an empty job list did not render the submit form: %s", rec.Body.String())
+	}
+}
+
+func TestUISubmitCreatesJobAndRedirects(t *testing.T) {
+
+	_, router := newTestUIServer(t)
+
+	form := strings.NewReader("urls=http%3A%2F%2F127.0.0.1%3A1%2Fx")
+	req := httptest.NewRequest(http.MethodPost, "/ui/jobs", form)
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusSeeOther {
+		t.Fatalf("POST /ui/jobs: status = %d, want %d", rec.Code, http.StatusSeeOther)
+	}
+
+	location := rec.Header().Get("Location")
+	if !strings.HasPrefix(location, "/ui/jobs/") {
+		t.Fatalf("Location = %q, want a /ui/jobs/{id} redirect", location)
+	}
+
+	detailReq := httptest.NewRequest(http.MethodGet, location, nil)
+	detailRec := httptest.NewRecorder()
+	router.ServeHTTP(detailRec, detailReq)
+
+	if detailRec.Code != http.StatusOK {
+		t.Fatalf("GET %s: status = %d, want %d", location, detailRec.Code, http.StatusOK)
+	}
+	if !strings.Contains(detailRec.Body.String(), "http://127.0.0.1:1/x") {
+		t.Errorf("job detail page missing the submitted URL: %s", detailRec.Body.String())
+	}
+}
+
+func TestUIJobDetailNotFound(t *testing.T) {
+
+	_, router := newTestUIServer(t)
+
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/ui/jobs/does-not-exist", nil)
+	router.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("GET /ui/jobs/does-not-exist: status = %d, want %d", rec.Code, http.StatusNotFound)
+	}
+}
+
+func TestSplitLines(t *testing.T) {
+
+	got := splitLines("https://a.example\n\n  https://b.example  \n")
+	want := []string{"https://a.example", "https://b.example"}
+
+	if len(got) != len(want) || got[0] != want[0] || got[1] != want[1] {
+		t.Fatalf("splitLines = %v, want %v", got, want)
+	}
+}
+
+func TestElementHistogram(t *testing.T) {
+
+	job := &Job{
+		Results: map[string]*ResponseItem{
+			"https://a.example": {Elements: []Element{{TagName: "p", Count: 2}, {TagName: "a", Count: 1}}},
+			"https://b.example": {Elements: []Element{{TagName: "p", Count: 3}}},
+		},
+	}
+
+	hist := elementHistogram(job)
+
+	if hist["p"] != 5 {
+		t.Errorf("hist[p] = %d, want 5", hist["p"])
+	}
+	if hist["a"] != 1 {
+		t.Errorf("hist[a] = %d, want 1", hist["a"])
+	}
+}